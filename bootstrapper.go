@@ -0,0 +1,302 @@
+package toxdynboot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+baseRefreshInterval is how soon a node that just answered a probe is checked
+again.
+*/
+const baseRefreshInterval = 5 * time.Minute
+
+/*
+initialResolveDelay and maxResolveDelay bound the exponential backoff applied
+to a node that fails its probe: the wait starts at initialResolveDelay and
+doubles on every further failure, up to maxResolveDelay. This mirrors the
+technique go-ethereum's dial resolver uses for unreachable peers.
+*/
+const (
+	initialResolveDelay = 60 * time.Second
+	maxResolveDelay     = time.Hour
+)
+
+/*
+sourceRefreshInterval is how often the Bootstrapper re-queries its Sources for
+newly added nodes.
+*/
+const sourceRefreshInterval = time.Hour
+
+/*
+defaultProbeTimeout is the base per-node probe timeout used when
+Config.ProbeTimeout is left at its zero value.
+*/
+const defaultProbeTimeout = 5 * time.Second
+
+/*
+Config configures a Bootstrapper. The zero value is valid: it falls back to
+the default Sources (wiki + nodes.tox.chat JSON) and defaultProbeTimeout. The
+Bootstrapper's pool is kept in memory only; it does not use a node cache on
+disk.
+*/
+type Config struct {
+	// Sources is queried for new nodes every sourceRefreshInterval. Defaults
+	// to WikiSource and JSONSource.
+	Sources []Source
+	// ProbeTimeout is the base per-node liveness probe timeout, scaled by
+	// each node's health score same as FetchAlive. Defaults to
+	// defaultProbeTimeout.
+	ProbeTimeout time.Duration
+}
+
+/*
+poolEntry is everything a Bootstrapper tracks about a single pool member
+between probes.
+*/
+type poolEntry struct {
+	node    ToxNode
+	alive   bool
+	backoff time.Duration
+	// running is true while a resolveLoop goroutine owns this entry. A
+	// Stop()+Start() cycle cancels every resolveLoop without removing their
+	// entries, so track() uses this to know which existing entries need a
+	// fresh resolveLoop rather than just a node-data merge.
+	running bool
+}
+
+/*
+Bootstrapper maintains an in-memory pool of Tox nodes that it refreshes in the
+background, so that Any and First can return immediately from a warm pool
+instead of every caller blocking on a fresh FetchAlive. This is the
+recommended embedding point for long-running Tox clients; FetchAnyAlive and
+friends remain the right choice for short-lived tools.
+*/
+type Bootstrapper struct {
+	cfg      Config
+	registry *Registry
+
+	mu   sync.Mutex
+	pool map[string]*poolEntry
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+/*
+NewBootstrapper builds a Bootstrapper from cfg. Call Start to begin refreshing
+it in the background.
+*/
+func NewBootstrapper(cfg Config) *Bootstrapper {
+	if cfg.ProbeTimeout == 0 {
+		cfg.ProbeTimeout = defaultProbeTimeout
+	}
+	if len(cfg.Sources) == 0 {
+		cfg.Sources = []Source{WikiSource{}, JSONSource{}}
+	}
+	return &Bootstrapper{
+		cfg:      cfg,
+		registry: NewRegistry(cfg.Sources...),
+		pool:     make(map[string]*poolEntry),
+	}
+}
+
+/*
+Start begins refreshing the pool in the background: an immediate source pull,
+then one every sourceRefreshInterval, with each discovered node probed on its
+own schedule (baseRefreshInterval while healthy, exponential backoff between
+initialResolveDelay and maxResolveDelay while failing).
+*/
+func (b *Bootstrapper) Start() {
+	if b.cancel != nil {
+		// already running; tear down the previous generation first so it
+		// doesn't keep probing in the background unsupervised
+		b.Stop()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.wg.Add(1)
+	go b.refreshSources(ctx)
+}
+
+/*
+Stop ends the background refresh and waits for every in-flight probe to
+return.
+*/
+func (b *Bootstrapper) Stop() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.wg.Wait()
+}
+
+/*
+Nodes returns every node the Bootstrapper currently knows about, alive or not.
+*/
+func (b *Bootstrapper) Nodes() []ToxNode {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	nodes := make([]ToxNode, 0, len(b.pool))
+	for _, entry := range b.pool {
+		nodes = append(nodes, entry.node)
+	}
+	return nodes
+}
+
+/*
+Alive returns the nodes last confirmed alive by the background refresh.
+*/
+func (b *Bootstrapper) Alive() []ToxNode {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var nodes []ToxNode
+	for _, entry := range b.pool {
+		if entry.alive {
+			nodes = append(nodes, entry.node)
+		}
+	}
+	return nodes
+}
+
+/*
+Any returns a random node from the warm pool, weighted towards the healthiest
+(see ToxNode.Health), or nil if none are currently known alive. Unlike
+FetchAnyAlive it never blocks on the network.
+*/
+func (b *Bootstrapper) Any() *ToxNode {
+	nodes := b.Alive()
+	if len(nodes) == 0 {
+		return nil
+	}
+	node := nodes[weightedChoice(nodes)]
+	return &node
+}
+
+/*
+First returns any one node from the warm pool, or nil if none are currently
+known alive. Unlike FetchFirstAlive it never blocks on the network.
+*/
+func (b *Bootstrapper) First() *ToxNode {
+	nodes := b.Alive()
+	if len(nodes) == 0 {
+		return nil
+	}
+	return &nodes[0]
+}
+
+/*
+refreshSources pulls the configured Sources immediately, then again every
+sourceRefreshInterval, until ctx is cancelled.
+*/
+func (b *Bootstrapper) refreshSources(ctx context.Context) {
+	defer b.wg.Done()
+	b.pullSources(ctx)
+	ticker := time.NewTicker(sourceRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.pullSources(ctx)
+		}
+	}
+}
+
+/*
+pullSources fetches the current node list and starts tracking any node not
+already in the pool.
+*/
+func (b *Bootstrapper) pullSources(ctx context.Context) {
+	nodes, err := b.registry.Fetch(ctx)
+	if err != nil {
+		// the next scheduled pull will try again; a transient source outage
+		// shouldn't take down the whole pool
+		return
+	}
+	for _, node := range nodes {
+		b.track(ctx, node)
+	}
+}
+
+/*
+track adds node to the pool if it isn't already tracked, merging in any new
+fields otherwise, and (re)starts its resolveLoop whenever one isn't already
+running for it - which covers both genuinely new nodes and nodes left over
+from a previous Start()/Stop() generation.
+*/
+func (b *Bootstrapper) track(ctx context.Context, node ToxNode) {
+	b.mu.Lock()
+	k := key(node.PublicKey)
+	entry, ok := b.pool[k]
+	if !ok {
+		entry = &poolEntry{node: node, backoff: initialResolveDelay}
+		b.pool[k] = entry
+	} else {
+		entry.node = mergeNodes(node, entry.node)
+	}
+	needsLoop := !entry.running
+	entry.running = true
+	b.mu.Unlock()
+
+	if needsLoop {
+		b.wg.Add(1)
+		go b.resolveLoop(ctx, k)
+	}
+}
+
+/*
+resolveLoop repeatedly probes the pool entry identified by k, rescheduling
+itself at baseRefreshInterval on success or at its current (doubling) backoff
+on failure, until ctx is cancelled or the entry is gone. It marks its entry as
+no longer running when it exits, so a later track() (from a restarted
+Bootstrapper) knows to start a fresh one.
+*/
+func (b *Bootstrapper) resolveLoop(ctx context.Context, k string) {
+	defer b.wg.Done()
+	defer func() {
+		b.mu.Lock()
+		if entry, ok := b.pool[k]; ok {
+			entry.running = false
+		}
+		b.mu.Unlock()
+	}()
+	for {
+		b.mu.Lock()
+		entry, ok := b.pool[k]
+		var node ToxNode
+		if ok {
+			node = entry.node
+		}
+		b.mu.Unlock()
+		if !ok {
+			return
+		}
+
+		// probe a local copy: entry.node can be rewritten by track() (e.g. a
+		// re-discovered node merging in new fields) concurrently with this
+		alive := isAliveContext(ctx, &node, b.cfg.ProbeTimeout)
+
+		b.mu.Lock()
+		entry.alive = alive
+		var wait time.Duration
+		if alive {
+			entry.backoff = initialResolveDelay
+			wait = baseRefreshInterval
+		} else {
+			wait = entry.backoff
+			entry.backoff *= 2
+			if entry.backoff > maxResolveDelay {
+				entry.backoff = maxResolveDelay
+			}
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package toxdynboot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+/*
+fakeSource is a Source over a fixed, in-memory node list, for tests that
+shouldn't depend on the network.
+*/
+type fakeSource struct {
+	nodes []ToxNode
+}
+
+func (f fakeSource) Fetch(ctx context.Context) ([]ToxNode, error) {
+	return f.nodes, nil
+}
+
+func Test_Bootstrapper_TracksAndStops(t *testing.T) {
+	fake := fakeSource{nodes: []ToxNode{
+		{IPv4: "192.0.2.1", Port: 33445, PublicKey: []byte("fake-node-key-0000000000000000AB")},
+	}}
+	b := NewBootstrapper(Config{Sources: []Source{fake}, ProbeTimeout: 50 * time.Millisecond})
+	b.Start()
+	defer b.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(b.Nodes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(b.Nodes()) != 1 {
+		t.Fatalf("expected the fake source's node to be tracked, got %d", len(b.Nodes()))
+	}
+}
+
+func Test_Bootstrapper_RestartSpawnsFreshResolveLoop(t *testing.T) {
+	fake := fakeSource{nodes: []ToxNode{
+		{IPv4: "192.0.2.1", Port: 33445, PublicKey: []byte("restart-node-key-00000000000000AB")},
+	}}
+	b := NewBootstrapper(Config{Sources: []Source{fake}, ProbeTimeout: 20 * time.Millisecond})
+
+	b.Start()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(b.Nodes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(b.Nodes()) != 1 {
+		t.Fatalf("expected the fake source's node to be tracked, got %d", len(b.Nodes()))
+	}
+	b.Stop()
+
+	b.mu.Lock()
+	var k string
+	for k = range b.pool {
+	}
+	if b.pool[k].running {
+		b.mu.Unlock()
+		t.Fatal("expected the resolveLoop to have stopped after Stop()")
+	}
+	b.mu.Unlock()
+
+	// a second Start() must re-track this already-known node, not just the
+	// newly discovered ones, or it's left with frozen liveness state forever
+	b.Start()
+	defer b.Stop()
+
+	deadline = time.Now().Add(2 * time.Second)
+	running := false
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		running = b.pool[k].running
+		b.mu.Unlock()
+		if running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !running {
+		t.Fatal("expected a fresh resolveLoop to be running for the pre-existing node after Start()")
+	}
+}
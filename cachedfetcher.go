@@ -0,0 +1,104 @@
+package toxdynboot
+
+import (
+	"context"
+	"time"
+)
+
+/*
+cacheSeedCount is how many cached nodes QuerySeeds is asked for before falling
+back to a fresh Source pull.
+*/
+const cacheSeedCount = 16
+
+/*
+cacheMaxAge is how recently a cached node must have answered a probe for it to
+be considered a usable seed without re-checking the network first.
+*/
+const cacheMaxAge = 30 * time.Minute
+
+/*
+CachedFetcher is a Registry backed by an on-disk nodeDB. Nodes seen or probed
+successfully are remembered across restarts, which spares the configured
+Sources (the wiki, nodes.tox.chat, ...) from being hit on every process start.
+*/
+type CachedFetcher struct {
+	registry *Registry
+	db       *nodeDB
+}
+
+/*
+NewCachedFetcher opens (or creates) a node cache at path and returns a fetcher
+that prefers it over sources. An empty path keeps the cache in memory only,
+which is mostly useful for tests.
+*/
+func NewCachedFetcher(path string, sources ...Source) (*CachedFetcher, error) {
+	db, err := openNodeDB(path)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedFetcher{registry: NewRegistry(sources...), db: db}, nil
+}
+
+/*
+Close releases the underlying node database.
+*/
+func (f *CachedFetcher) Close() {
+	f.db.Close()
+}
+
+/*
+FetchAnyAlive returns a node known to be alive, preferring the cache: nodes
+that answered a probe within cacheMaxAge are probed again, most recently
+successful first, and the first one that answers is returned directly. Only
+if none answers are the configured sources queried fresh; their result is
+folded into the cache for next time and probed in order. It is
+FetchAnyAliveContext with a context bounded by defaultHTTPTimeout.
+*/
+func (f *CachedFetcher) FetchAnyAlive(timeout time.Duration) (*ToxNode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	return f.FetchAnyAliveContext(ctx, timeout)
+}
+
+/*
+FetchAnyAliveContext is FetchAnyAlive with a caller-supplied context, so the
+fallback Source pull it may need to make is bounded by ctx instead of blocking
+forever on a slow or hanging source.
+*/
+func (f *CachedFetcher) FetchAnyAliveContext(ctx context.Context, timeout time.Duration) (*ToxNode, error) {
+	for _, node := range f.db.QuerySeeds(cacheSeedCount, cacheMaxAge) {
+		node := node
+		if f.probe(ctx, &node, timeout) {
+			return &node, nil
+		}
+	}
+	nodes, err := f.registry.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range nodes {
+		f.db.UpdateNode(node, "registry")
+	}
+	for _, node := range nodes {
+		node := node
+		if f.probe(ctx, &node, timeout) {
+			return &node, nil
+		}
+	}
+	return nil, nil
+}
+
+/*
+probe checks whether node is alive, timing the check and recording the
+outcome (success with RTT, or failure) in the cache.
+*/
+func (f *CachedFetcher) probe(ctx context.Context, node *ToxNode, timeout time.Duration) bool {
+	start := time.Now()
+	if isAliveContext(ctx, node, timeout) {
+		f.db.LastPongReceived(node.PublicKey, time.Since(start))
+		return true
+	}
+	f.db.MarkFailure(node.PublicKey)
+	return false
+}
@@ -0,0 +1,121 @@
+package toxdynboot
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+/*
+listenAndAccept starts a TCP listener that accepts (and immediately closes)
+every connection, so isAliveTCPHost sees it as reachable. Used to fake an
+alive node without talking to the real Tox DHT protocol.
+*/
+func listenAndAccept(t *testing.T) (host string, port uint16) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), uint16(addr.Port)
+}
+
+/*
+spySource counts how many times Fetch is called, so tests can assert whether
+the cache was enough on its own.
+*/
+type spySource struct {
+	nodes []ToxNode
+	calls *int
+}
+
+func (s spySource) Fetch(ctx context.Context) ([]ToxNode, error) {
+	*s.calls++
+	return s.nodes, nil
+}
+
+func withTCPProbe(t *testing.T) {
+	t.Helper()
+	previous := UseTCPProbe
+	UseTCPProbe = true
+	t.Cleanup(func() { UseTCPProbe = previous })
+}
+
+func Test_CachedFetcher_PrefersCacheOverSource(t *testing.T) {
+	withTCPProbe(t)
+	host, port := listenAndAccept(t)
+
+	dir, err := ioutil.TempDir("", "toxdynboot-cachedfetcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	source := spySource{calls: &calls}
+	fetcher, err := NewCachedFetcher(dir+"/nodes.json", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fetcher.Close()
+
+	node := ToxNode{IPv4: host, Port: port, PublicKey: []byte("cached-node-key-000000000000000X")}
+	fetcher.db.UpdateNode(node, "test")
+	fetcher.db.LastPongReceived(node.PublicKey, time.Millisecond)
+
+	found, err := fetcher.FetchAnyAliveContext(context.Background(), 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.IPv4 != host {
+		t.Fatalf("expected the cached node back, got %v", found)
+	}
+	if calls != 0 {
+		t.Errorf("expected the source not to be queried while the cache had a fresh seed, got %d calls", calls)
+	}
+}
+
+func Test_CachedFetcher_FallsBackToSourceWhenCacheIsEmpty(t *testing.T) {
+	withTCPProbe(t)
+	host, port := listenAndAccept(t)
+
+	dir, err := ioutil.TempDir("", "toxdynboot-cachedfetcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	node := ToxNode{IPv4: host, Port: port, PublicKey: []byte("fallback-node-key-00000000000000X")}
+	source := spySource{nodes: []ToxNode{node}, calls: &calls}
+	fetcher, err := NewCachedFetcher(dir+"/nodes.json", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fetcher.Close()
+
+	found, err := fetcher.FetchAnyAliveContext(context.Background(), 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.IPv4 != host {
+		t.Fatalf("expected the source's node back, got %v", found)
+	}
+	if calls != 1 {
+		t.Errorf("expected the source to be queried once the cache came up empty, got %d calls", calls)
+	}
+}
@@ -6,10 +6,11 @@ import "errors"
 Errors used.
 */
 var (
-	errSourceFormat = errors.New("source can not be parsed")
-	errSourceTable  = errors.New("source table not parseable")
-	errAliveTimeout = errors.New("alive timed out")
-	errNoToxNodes   = errors.New("no ToxNodes could be fetched")
+	errSourceFormat  = errors.New("source can not be parsed")
+	errSourceTable   = errors.New("source table not parseable")
+	errAliveTimeout  = errors.New("alive timed out")
+	errNoToxNodes    = errors.New("no ToxNodes could be fetched")
+	errNodeDBVersion = errors.New("node database version mismatch")
 )
 
 /*
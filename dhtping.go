@@ -0,0 +1,147 @@
+package toxdynboot
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+/*
+Packet types used on the wire for the Tox DHT requests relevant to PingDHT.
+*/
+const (
+	packetGetNodes  = 0x02
+	packetSendNodes = 0x04
+)
+
+/*
+dhtReadBuffer is large enough for a send_nodes reply, which is the biggest
+packet PingDHT expects to receive.
+*/
+const dhtReadBuffer = 2048
+
+/*
+PingDHT sends a get_nodes request to node's IPv4 address over UDP and returns
+true if a send_nodes reply echoing our ping_id arrives before timeout. This
+talks the actual Tox DHT protocol, so unlike isAliveTCPHost it isn't fooled by a
+node that is up but silently drops unexpected connections.
+
+The request is encrypted with NaCl secretbox using a shared key precomputed
+from an ephemeral Curve25519 keypair and the node's PublicKey, and framed as
+[type(1) || sender_pk(32) || nonce(24) || ciphertext].
+*/
+func PingDHT(node *ToxNode, timeout time.Duration) bool {
+	return pingDHTHost(context.Background(), node.IPv4, node, timeout)
+}
+
+/*
+pingDHTHost is PingDHT generalized over which of node's addresses (IPv4 or
+IPv6) to dial, and cancellable via ctx - used by isAliveContext to race both
+address families.
+*/
+func pingDHTHost(ctx context.Context, host string, node *ToxNode, timeout time.Duration) bool {
+	if host == "" || len(node.PublicKey) != 32 {
+		return false
+	}
+	network := "udp4"
+	if strings.Contains(host, ":") {
+		network = "udp6"
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, strconv.FormatUint(uint64(node.Port), 10)))
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	// abort the blocking Read below as soon as ctx is done, even if that's
+	// before our own deadline (e.g. the sibling address family already won)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	ourPublic, ourPrivate, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return false
+	}
+	var theirPublic [32]byte
+	copy(theirPublic[:], node.PublicKey)
+	var shared [32]byte
+	box.Precompute(&shared, &theirPublic, ourPrivate)
+
+	var pingID [8]byte
+	if _, err := rand.Read(pingID[:]); err != nil {
+		return false
+	}
+	plain := append(append([]byte{}, node.PublicKey...), pingID[:]...)
+
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return false
+	}
+	ciphertext := secretbox.Seal(nil, plain, &nonce, &shared)
+
+	packet := make([]byte, 0, 1+32+24+len(ciphertext))
+	packet = append(packet, packetGetNodes)
+	packet = append(packet, ourPublic[:]...)
+	packet = append(packet, nonce[:]...)
+	packet = append(packet, ciphertext...)
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return false
+	}
+
+	buf := make([]byte, dhtReadBuffer)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			// deadline reached, or the socket otherwise gave up
+			return false
+		}
+		if isSendNodesReply(buf[:n], &shared, &pingID) {
+			return true
+		}
+		// not the reply we're waiting for; keep listening until the deadline
+	}
+}
+
+/*
+isSendNodesReply reports whether packet is a send_nodes reply that decrypts
+under shared and echoes pingID.
+*/
+func isSendNodesReply(packet []byte, shared *[32]byte, pingID *[8]byte) bool {
+	// type(1) + sender_pk(32) + nonce(24) + sealed ping_id
+	if len(packet) < 1+32+24+secretbox.Overhead+len(pingID) {
+		return false
+	}
+	if packet[0] != packetSendNodes {
+		return false
+	}
+	var nonce [24]byte
+	copy(nonce[:], packet[1+32:1+32+24])
+	plain, ok := secretbox.Open(nil, packet[1+32+24:], &nonce, shared)
+	if !ok || len(plain) < len(pingID) {
+		return false
+	}
+	for i := range pingID {
+		if plain[i] != pingID[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package toxdynboot
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+func Test_IsSendNodesReply(t *testing.T) {
+	var shared [32]byte
+	if _, err := rand.Read(shared[:]); err != nil {
+		t.Fatal(err)
+	}
+	var pingID [8]byte
+	if _, err := rand.Read(pingID[:]); err != nil {
+		t.Fatal(err)
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := secretbox.Seal(nil, pingID[:], &nonce, &shared)
+	packet := append([]byte{packetSendNodes}, make([]byte, 32)...)
+	packet = append(packet, nonce[:]...)
+	packet = append(packet, ciphertext...)
+
+	if !isSendNodesReply(packet, &shared, &pingID) {
+		t.Error("expected a matching send_nodes packet to be recognized as alive")
+	}
+	// wrong ping_id must not be accepted
+	var otherID [8]byte
+	if !bytesDiffer(pingID[:], otherID[:]) {
+		t.Fatal("test setup produced colliding ping ids")
+	}
+	if isSendNodesReply(packet, &shared, &otherID) {
+		t.Error("expected a reply echoing a different ping_id to be rejected")
+	}
+}
+
+func bytesDiffer(a, b []byte) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package toxdynboot
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+healthMaxScore bounds the awareness score below: a node can never be judged
+worse than healthMaxScore.
+*/
+const healthMaxScore = 8
+
+/*
+health is a small awareness counter in [0, healthMaxScore], modeled on
+hashicorp/memberlist's node awareness: it climbs by one on every failed probe
+(bounded at healthMaxScore) and drops by one on every successful one (floored
+at zero). Zero is healthiest.
+*/
+type health struct {
+	mu    sync.Mutex
+	score int
+}
+
+/*
+ApplyDelta adjusts the score by delta, clamped to [0, healthMaxScore].
+*/
+func (h *health) ApplyDelta(delta int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.score += delta
+	if h.score < 0 {
+		h.score = 0
+	}
+	if h.score > healthMaxScore {
+		h.score = healthMaxScore
+	}
+}
+
+/*
+Score returns the current score.
+*/
+func (h *health) Score() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.score
+}
+
+/*
+healthRegistry tracks a health score per node, keyed by the hex encoding of
+its PublicKey. ToxNodes themselves are rebuilt from scratch on every Fetch*
+call, so the scores have to live somewhere that outlives any single ToxNode
+value.
+*/
+type healthRegistry struct {
+	mu     sync.Mutex
+	scores map[string]*health
+}
+
+/*
+defaultHealth backs ToxNode.Health, HealthMetrics and the isAlive probe in
+liveness.go.
+*/
+var defaultHealth = &healthRegistry{scores: make(map[string]*health)}
+
+func (r *healthRegistry) get(publicKey []byte) *health {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := key(publicKey)
+	h, ok := r.scores[k]
+	if !ok {
+		h = &health{}
+		r.scores[k] = h
+	}
+	return h
+}
+
+/*
+Health returns t's current awareness score in [0, healthMaxScore]; 0 means
+healthiest.
+*/
+func (t *ToxNode) Health() int {
+	return defaultHealth.get(t.PublicKey).Score()
+}
+
+/*
+HealthMetrics is a point-in-time snapshot of every node's health score this
+package currently knows about, keyed by the hex encoding of PublicKey. Meant
+for observability, e.g. exposing as a debug endpoint.
+*/
+func HealthMetrics() map[string]int {
+	defaultHealth.mu.Lock()
+	defer defaultHealth.mu.Unlock()
+	snapshot := make(map[string]int, len(defaultHealth.scores))
+	for k, h := range defaultHealth.scores {
+		snapshot[k] = h.Score()
+	}
+	return snapshot
+}
+
+/*
+packageRand is this package's single math/rand source, guarded by
+packageRandMu. Previously every random pick reseeded math/rand's global
+source with rand.Seed(time.Now().UnixNano()); one seed for the process
+lifetime is plenty, and reseeding on every call isn't even safe if two picks
+happen concurrently.
+*/
+var (
+	packageRandMu sync.Mutex
+	packageRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+/*
+randIntn returns a random int in [0, n) drawn from packageRand.
+*/
+func randIntn(n int) int {
+	packageRandMu.Lock()
+	defer packageRandMu.Unlock()
+	return packageRand.Intn(n)
+}
+
+/*
+weightedChoice picks a random index into nodes, weighted by 1/(score+1) so a
+healthy node is preferred over one that's merely still alive.
+*/
+func weightedChoice(nodes []ToxNode) int {
+	weights := make([]float64, len(nodes))
+	var total float64
+	for i, node := range nodes {
+		weights[i] = 1 / float64(node.Health()+1)
+		total += weights[i]
+	}
+	packageRandMu.Lock()
+	r := packageRand.Float64() * total
+	packageRandMu.Unlock()
+	for i, w := range weights {
+		r -= w
+		if r <= 0 {
+			return i
+		}
+	}
+	return len(nodes) - 1
+}
@@ -0,0 +1,34 @@
+package toxdynboot
+
+import "testing"
+
+func Test_Health_ApplyDelta_Bounds(t *testing.T) {
+	h := &health{}
+	for i := 0; i < healthMaxScore+5; i++ {
+		h.ApplyDelta(1)
+	}
+	if h.Score() != healthMaxScore {
+		t.Errorf("expected score to clamp at %d, got %d", healthMaxScore, h.Score())
+	}
+	for i := 0; i < healthMaxScore+5; i++ {
+		h.ApplyDelta(-1)
+	}
+	if h.Score() != 0 {
+		t.Errorf("expected score to floor at 0, got %d", h.Score())
+	}
+}
+
+func Test_WeightedChoice_PrefersHealthiest(t *testing.T) {
+	healthy := ToxNode{PublicKey: []byte("healthy-node-key-000000000000000")}
+	flaky := ToxNode{PublicKey: []byte("flaky-node-key-0000000000000000")}
+	defaultHealth.get(flaky.PublicKey).ApplyDelta(healthMaxScore)
+
+	counts := map[string]int{}
+	nodes := []ToxNode{healthy, flaky}
+	for i := 0; i < 2000; i++ {
+		counts[nodes[weightedChoice(nodes)].String()]++
+	}
+	if counts[healthy.String()] <= counts[flaky.String()] {
+		t.Errorf("expected the healthy node to be picked more often, got %v", counts)
+	}
+}
@@ -0,0 +1,137 @@
+package toxdynboot
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+UseTCPProbe switches the liveness checks in this package from the DHT-level
+PingDHT probe back to the legacy isAliveTCPHost dial check below. Only needed in
+environments that block outbound UDP, where PingDHT can never succeed.
+*/
+var UseTCPProbe = false
+
+/*
+happyEyeballsIPv6HeadStart is how much of a start the IPv6 probe gets before
+its IPv4 sibling is launched, per RFC 6555 ("Happy Eyeballs"). IPv6, being the
+modern path, is given the benefit of the doubt.
+*/
+const happyEyeballsIPv6HeadStart = 50 * time.Millisecond
+
+/*
+isAlive checks whether node is reachable within base (scaled by its current
+health score, see ToxNode.Health), racing its IPv4 and IPv6 addresses Happy
+Eyeballs style. It is the non-context entry point kept for existing callers
+and tests; isAliveContext is the real implementation.
+*/
+func isAlive(node *ToxNode, base time.Duration) bool {
+	return isAliveContext(context.Background(), node, base)
+}
+
+/*
+isAliveContext is isAlive with a caller-supplied context, so a fetch that gets
+cancelled doesn't leave probes running past it. If node has both an IPv4 and
+an IPv6 address, both are probed concurrently - the IPv6 attempt gets a
+happyEyeballsIPv6HeadStart lead - and the first to succeed wins, cancelling
+the other. Either a successful or failed outcome feeds back into the node's
+health score.
+*/
+func isAliveContext(ctx context.Context, node *ToxNode, base time.Duration) bool {
+	timeout := base * time.Duration(node.Health()+1)
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var hosts []string
+	if node.IPv6 != "" {
+		hosts = append(hosts, node.IPv6)
+	}
+	if node.IPv4 != "" {
+		hosts = append(hosts, node.IPv4)
+	}
+	if len(hosts) == 0 {
+		return false
+	}
+
+	raceCtx, cancelRace := context.WithCancel(deadlineCtx)
+	defer cancelRace()
+
+	results := make(chan bool, len(hosts))
+	for _, host := range hosts {
+		host := host
+		var headStart time.Duration
+		if len(hosts) > 1 && host == node.IPv4 {
+			headStart = happyEyeballsIPv6HeadStart
+		}
+		go func() {
+			if headStart > 0 {
+				select {
+				case <-time.After(headStart):
+				case <-raceCtx.Done():
+					results <- false
+					return
+				}
+			}
+			alive := probeHost(raceCtx, host, node, timeout)
+			if alive {
+				// we have a winner, let the other family give up early
+				cancelRace()
+			}
+			results <- alive
+		}()
+	}
+
+	alive := false
+	for range hosts {
+		if <-results {
+			alive = true
+		}
+	}
+	if alive {
+		defaultHealth.get(node.PublicKey).ApplyDelta(-1)
+	} else {
+		defaultHealth.get(node.PublicKey).ApplyDelta(1)
+	}
+	return alive
+}
+
+/*
+probeHost dispatches a single-address liveness probe to either PingDHT or the
+legacy isAliveTCPHost check, depending on UseTCPProbe.
+*/
+func probeHost(ctx context.Context, host string, node *ToxNode, timeout time.Duration) bool {
+	if UseTCPProbe {
+		return isAliveTCPHost(ctx, host, node.Port, timeout)
+	}
+	return pingDHTHost(ctx, host, node, timeout)
+}
+
+/*
+isAliveTCPHost checks whether host is reachable over plain TCP, cancellable
+via ctx. This is the legacy probe used when UseTCPProbe is set. NOTE: this
+relies on nodes refusing connections - if they are online but quietly discard
+connection attempts, this function will wrongly label them as unreachable
+(which is the case for a few of the current nodes as of 2015.06.10); PingDHT
+is the more accurate default.
+*/
+func isAliveTCPHost(ctx context.Context, host string, port uint16, timeout time.Duration) bool {
+	if host == "" {
+		return false
+	}
+	address := net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+	dialer := net.Dialer{Timeout: timeout}
+	// since ICMP ping is not trivially available we rely on the servers denying TCP connections as a ping
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	// if err but not 'connection refused' --> unreachable for ping
+	if err != nil && !strings.Contains(err.Error(), "connection refused") {
+		return false
+	} // else if conn ok or conn refused --> alive
+	// if conn happened make sure to close it as we don't need it
+	if conn != nil {
+		conn.Close()
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package toxdynboot
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+/*
+fakeDHTServer listens on a UDP loopback address and speaks just enough of the
+get_nodes/send_nodes exchange to answer pingDHTHost: it decrypts the request
+under the shared key derived from its own static keypair and the caller's
+ephemeral one, then echoes the ping_id back in a send_nodes reply. If silent
+is true it never replies, to stand in for an unreachable node.
+*/
+func fakeDHTServer(t *testing.T, silent bool, ip net.IP, port int) (host string, boundPort uint16, publicKey []byte) {
+	t.Helper()
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip, Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, dhtReadBuffer)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if silent {
+				continue
+			}
+			if n < 1+32+24 {
+				continue
+			}
+			var senderEphemeral [32]byte
+			copy(senderEphemeral[:], buf[1:33])
+			var nonce [24]byte
+			copy(nonce[:], buf[33:57])
+			var shared [32]byte
+			box.Precompute(&shared, &senderEphemeral, priv)
+			plain, ok := secretbox.Open(nil, buf[57:n], &nonce, &shared)
+			if !ok || len(plain) < 40 {
+				continue
+			}
+			pingID := plain[32:40]
+
+			var replyNonce [24]byte
+			if _, err := rand.Read(replyNonce[:]); err != nil {
+				continue
+			}
+			ciphertext := secretbox.Seal(nil, pingID, &replyNonce, &shared)
+			reply := make([]byte, 0, 1+32+24+len(ciphertext))
+			reply = append(reply, packetSendNodes)
+			reply = append(reply, pub[:]...)
+			reply = append(reply, replyNonce[:]...)
+			reply = append(reply, ciphertext...)
+			conn.WriteToUDP(reply, addr)
+		}
+	}()
+
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	return addr.IP.String(), uint16(addr.Port), pub[:]
+}
+
+func Test_IsAliveContext_SingleAddressFamilyResolves(t *testing.T) {
+	host, port, publicKey := fakeDHTServer(t, false, net.IPv4(127, 0, 0, 1), 0)
+	node := &ToxNode{IPv4: host, Port: port, PublicKey: publicKey}
+
+	if !isAliveContext(context.Background(), node, 200*time.Millisecond) {
+		t.Error("expected the node with only an IPv4 address to resolve alive")
+	}
+}
+
+func Test_IsAliveContext_CancelsTheLosingProbe(t *testing.T) {
+	winnerHost, winnerPort, winnerKey := fakeDHTServer(t, false, net.IPv4(127, 0, 0, 1), 0)
+	// isAliveContext dials both addresses on the same node.Port, so the loser
+	// has to listen on a distinct loopback IP to share that port number
+	loserHost, _, _ := fakeDHTServer(t, true, net.IPv4(127, 0, 0, 2), int(winnerPort))
+
+	// the IPv6 field is only ever used as an address string by isAliveContext,
+	// so the silent server stands in for it even though it's really IPv4
+	node := &ToxNode{
+		IPv4:      winnerHost,
+		IPv6:      loserHost,
+		Port:      winnerPort,
+		PublicKey: winnerKey,
+	}
+
+	const timeout = 300 * time.Millisecond
+	start := time.Now()
+	if !isAliveContext(context.Background(), node, timeout) {
+		t.Fatal("expected the winning (responsive) address to be reported alive")
+	}
+	elapsed := time.Since(start)
+
+	// if the losing probe's blocked Read were not cancelled when the winner
+	// answered, this call would take the full (scaled) timeout to return
+	if elapsed > timeout/2 {
+		t.Errorf("expected the losing probe to be cancelled promptly, but isAliveContext took %v (timeout was %v)", elapsed, timeout)
+	}
+}
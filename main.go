@@ -6,10 +6,9 @@ function.
 package toxdynboot
 
 import (
+	"context"
 	"encoding/hex"
 	"io/ioutil"
-	"math/rand"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -17,17 +16,38 @@ import (
 )
 
 /*
-FetchAll returns all nodes that are in the wiki.
+defaultHTTPTimeout bounds the non-context Fetch* wrappers' HTTP requests,
+standing in for the context.Context timeout their *Context counterparts let
+callers set explicitly.
+*/
+const defaultHTTPTimeout = 30 * time.Second
+
+/*
+FetchAll returns all nodes known to the default Registry (currently the wiki
+and the nodes.tox.chat JSON endpoint). To query a custom set of sources
+instead, use FetchAllFrom directly. It is FetchAllContext with
+context.Background() and defaultHTTPTimeout.
 */
 func FetchAll() ([]ToxNode, error) {
-	return parseNodes()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	return FetchAllContext(ctx)
+}
+
+/*
+FetchAllContext is FetchAll with a caller-supplied context, letting the HTTP
+requests it makes be cancelled or bounded by the caller instead of
+defaultHTTPTimeout.
+*/
+func FetchAllContext(ctx context.Context) ([]ToxNode, error) {
+	return defaultRegistry.Fetch(ctx)
 }
 
 /*
-FetchAny returns a random single node from the wiki.
+FetchAny returns a random single node from the default Registry.
 */
 func FetchAny() (*ToxNode, error) {
-	nodesTemp, err := parseNodes()
+	nodesTemp, err := FetchAll()
 	if err != nil {
 		return nil, err
 	}
@@ -36,21 +56,31 @@ func FetchAny() (*ToxNode, error) {
 		return nil, nil
 	}
 	nodes := nodesTemp
-	// random seed based on time (doesn't need to be cryptographically secure)
-	rand.Seed(time.Now().UnixNano())
 	// pick one random
-	node := nodes[rand.Intn(len(nodes))]
+	node := nodes[randIntn(len(nodes))]
 	return &node, nil
 }
 
 /*
 FetchAlive fetches all nodes from the wiki and then checks whether they are actively
 reachable and only returns those. Note that this means that this function will block for
-the specified time!
+the specified time! It is FetchAliveContext with context.Background() and
+defaultHTTPTimeout.
 */
 func FetchAlive(timeout time.Duration) ([]ToxNode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	return FetchAliveContext(ctx, timeout)
+}
+
+/*
+FetchAliveContext is FetchAlive with a caller-supplied context: ctx bounds the
+node list fetch, while timeout still governs each individual liveness probe
+(itself also cancelled if ctx is done first).
+*/
+func FetchAliveContext(ctx context.Context, timeout time.Duration) ([]ToxNode, error) {
 	// we'll only check those marked as active
-	nodes, err := parseNodes()
+	nodes, err := defaultRegistry.Fetch(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +88,7 @@ func FetchAlive(timeout time.Duration) ([]ToxNode, error) {
 	for _, node := range nodes {
 		// concurrently do this because it locks.
 		go func(node ToxNode) {
-			if isAlive(&node, timeout) {
+			if isAliveContext(ctx, &node, timeout) {
 				c <- &node
 			} else {
 				c <- nil
@@ -80,10 +110,20 @@ func FetchAlive(timeout time.Duration) ([]ToxNode, error) {
 /*
 FetchAnyAlive will retrive a random node of those that have been determined to be alive
 within the given timeout. This is the method you should probably use to bootstrap
-a client with multiple Tox nodes.
+a client with multiple Tox nodes. It is FetchAnyAliveContext with context.Background()
+and defaultHTTPTimeout.
 */
 func FetchAnyAlive(timeout time.Duration) (*ToxNode, error) {
-	nodesTemp, err := FetchAlive(timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	return FetchAnyAliveContext(ctx, timeout)
+}
+
+/*
+FetchAnyAliveContext is FetchAnyAlive with a caller-supplied context.
+*/
+func FetchAnyAliveContext(ctx context.Context, timeout time.Duration) (*ToxNode, error) {
+	nodesTemp, err := FetchAliveContext(ctx, timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -92,20 +132,28 @@ func FetchAnyAlive(timeout time.Duration) (*ToxNode, error) {
 		return nil, nil
 	}
 	nodes := nodesTemp
-	// random seed based on time (doesn't need to be cryptographically secure)
-	rand.Seed(time.Now().UnixNano())
-	// pick one random
-	node := nodes[rand.Intn(len(nodes))]
+	// pick one, weighted towards the healthiest (see ToxNode.Health)
+	node := nodes[weightedChoice(nodes)]
 	return &node, nil
 }
 
 /*
 FetchFirstAlive will return the first node that we determine to be available. The timeout
-is the max time: if reached the function will return an error.
+is the max time: if reached the function will return an error. It is
+FetchFirstAliveContext with context.Background() and defaultHTTPTimeout.
 */
 func FetchFirstAlive(timeout time.Duration) (*ToxNode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	return FetchFirstAliveContext(ctx, timeout)
+}
+
+/*
+FetchFirstAliveContext is FetchFirstAlive with a caller-supplied context.
+*/
+func FetchFirstAliveContext(ctx context.Context, timeout time.Duration) (*ToxNode, error) {
 	// we'll only check those marked as active
-	nodes, err := parseNodes()
+	nodes, err := defaultRegistry.Fetch(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +165,7 @@ func FetchFirstAlive(timeout time.Duration) (*ToxNode, error) {
 	for _, node := range nodes {
 		// concurrently do this because it locks.
 		go func(node ToxNode) {
-			if isAlive(&node, timeout) {
+			if isAliveContext(ctx, &node, timeout) {
 				c <- &node
 			} else {
 				c <- nil
@@ -135,9 +183,12 @@ func FetchFirstAlive(timeout time.Duration) (*ToxNode, error) {
 /*
 parseNodes reads the possible bootstrap nodes from the wiki. Requires active internet!
 */
-func parseNodes() ([]ToxNode, error) {
-	// TODO: this can block for a long time – implement timeout?
-	response, err := http.Get(toxWikiNodesURL)
+func parseNodes(ctx context.Context) ([]ToxNode, error) {
+	request, err := http.NewRequest(http.MethodGet, toxWikiNodesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -208,26 +259,3 @@ func parseNodes() ([]ToxNode, error) {
 	// return full list
 	return nodes, nil
 }
-
-/*
-IsAlive checks whether the given ToxNode is reachable. NOTE: this relies on nodes refusing
-connections - if they are online but quietly discard connection attempts, this function
-will wrongly label them as unreachable (which is the case for a few of the current nodes
-as of 2015.06.10).
-*/
-func isAlive(node *ToxNode, timeout time.Duration) bool {
-	// TODO: use both IPv4 AND IPv6.
-	address := node.IPv4 + ":" + strconv.FormatUint(uint64(node.Port), 10)
-	// since ICMP ping is not trivially available we rely on the servers denying TCP connections as a ping
-	conn, err := net.DialTimeout("tcp", address, timeout)
-	// if err but not 'connection refused' --> unreachable for ping
-	if err != nil && !strings.Contains(err.Error(), "connection refused") {
-		// log.Printf("Node %s is unreachable!", node.IPv4)
-		return false
-	} // else if conn ok or conn refused --> alive
-	// if conn happened make sure to close it as we don't need it
-	if conn != nil {
-		conn.Close()
-	}
-	return true
-}
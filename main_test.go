@@ -1,12 +1,14 @@
 package toxdynboot
 
 import (
+	"bytes"
+	"context"
 	"testing"
 	"time"
 )
 
 func Test_ParseNodes(t *testing.T) {
-	nodes, err := parseNodes()
+	nodes, err := parseNodes(context.Background())
 	if err != nil {
 		t.Fatal("ParseNodes:", err)
 	}
@@ -16,12 +18,13 @@ func Test_ParseNodes(t *testing.T) {
 }
 
 func Test_IsAlive(t *testing.T) {
-	// fake node
+	// fake node; PublicKey must be a real 32 bytes or PingDHT rejects it
+	// before ever dialing, which would defeat the point of this test
 	node := &ToxNode{
 		IPv4:      "0.1.2.3",
 		IPv6:      "",
 		Port:      12345,
-		PublicKey: []byte("Invalid")}
+		PublicKey: bytes.Repeat([]byte("x"), 32)}
 	// test timeout
 	f := func() chan bool {
 		recv := make(chan bool, 1)
@@ -0,0 +1,298 @@
+package toxdynboot
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+nodeDBVersion is stored alongside the cached records. Bumping it invalidates
+every existing on-disk cache the next time it is opened, the same trick
+go-ethereum's p2p/discover node database uses to deal with schema changes.
+*/
+const nodeDBVersion = 1
+
+/*
+nodeDBExpiration is how long a node may go without being reported by a Source
+fetch or successfully probed before the expirer drops it from the cache.
+*/
+const nodeDBExpiration = 24 * time.Hour
+
+/*
+nodeDBExpireInterval is how often the expirer sweeps the cache for stale
+records.
+*/
+const nodeDBExpireInterval = time.Hour
+
+/*
+maxRTTSamples bounds how many round-trip-time samples nodeRecord.RTTs keeps;
+older samples are dropped in favor of newer ones.
+*/
+const maxRTTSamples = 8
+
+/*
+nodeRecord is everything nodeDB remembers about a single node, keyed by the
+hex encoding of its PublicKey.
+*/
+type nodeRecord struct {
+	Node        ToxNode
+	Source      string
+	LastSeen    time.Time
+	LastSuccess time.Time
+	LastFail    time.Time
+	Fails       int
+	RTTs        []time.Duration
+}
+
+/*
+nodeDB is a small on-disk cache of nodeRecords, modeled after the database
+go-ethereum's p2p/discover package keeps for DHT nodes: it survives restarts,
+wipes itself on a version bump, and expires entries nobody has seen or
+probed recently. It is stored as a single JSON file for simplicity; nothing
+about its API depends on that choice.
+*/
+type nodeDB struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]*nodeRecord
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+/*
+nodeDBFile is the on-disk representation of a nodeDB.
+*/
+type nodeDBFile struct {
+	Version int
+	Records []*nodeRecord
+}
+
+/*
+openNodeDB opens (or creates) the cache at path. If path is empty the cache is
+kept in memory only for the lifetime of the process. A version mismatch (or
+any file we can't parse) results in starting fresh rather than erroring out,
+since the cache is only ever an optimization.
+*/
+func openNodeDB(path string) (*nodeDB, error) {
+	db := &nodeDB{
+		path:    path,
+		records: make(map[string]*nodeRecord),
+		closeCh: make(chan struct{}),
+	}
+	if path != "" {
+		if err := db.load(); err != nil {
+			// treat a corrupt or outdated cache as empty rather than fatal
+			db.records = make(map[string]*nodeRecord)
+		}
+	}
+	go db.expirer()
+	return db, nil
+}
+
+/*
+load reads and validates the on-disk file, replacing db.records on success.
+*/
+func (db *nodeDB) load() error {
+	contents, err := ioutil.ReadFile(db.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var file nodeDBFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return err
+	}
+	if file.Version != nodeDBVersion {
+		return errNodeDBVersion
+	}
+	records := make(map[string]*nodeRecord, len(file.Records))
+	for _, record := range file.Records {
+		records[hex.EncodeToString(record.Node.PublicKey)] = record
+	}
+	db.records = records
+	return nil
+}
+
+/*
+flush persists the current records to disk. Callers must hold db.mu.
+*/
+func (db *nodeDB) flush() error {
+	if db.path == "" {
+		return nil
+	}
+	file := nodeDBFile{Version: nodeDBVersion, Records: make([]*nodeRecord, 0, len(db.records))}
+	for _, record := range db.records {
+		file.Records = append(file.Records, record)
+	}
+	contents, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(db.path, contents, 0o600)
+}
+
+/*
+key returns the map key a node is stored/looked up under.
+*/
+func key(publicKey []byte) string {
+	return hex.EncodeToString(publicKey)
+}
+
+/*
+UpdateNode records that node was reported by source, refreshing LastSeen and
+filling in any data the cache didn't have yet.
+*/
+func (db *nodeDB) UpdateNode(node ToxNode, source string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	k := key(node.PublicKey)
+	record, ok := db.records[k]
+	if !ok {
+		record = &nodeRecord{Node: node, Source: source}
+		db.records[k] = record
+	} else {
+		record.Node = mergeNodes(node, record.Node)
+	}
+	record.LastSeen = time.Now()
+	_ = db.flush()
+}
+
+/*
+LastPongReceived records a successful probe of the node identified by
+publicKey, resetting its failure count and recording rtt as the newest RTT
+sample.
+*/
+func (db *nodeDB) LastPongReceived(publicKey []byte, rtt time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	record, ok := db.records[key(publicKey)]
+	if !ok {
+		return
+	}
+	record.LastSuccess = time.Now()
+	record.Fails = 0
+	record.RTTs = append(record.RTTs, rtt)
+	if len(record.RTTs) > maxRTTSamples {
+		record.RTTs = record.RTTs[len(record.RTTs)-maxRTTSamples:]
+	}
+	_ = db.flush()
+}
+
+/*
+MarkFailure records a failed probe of the node identified by publicKey,
+incrementing its failure count.
+*/
+func (db *nodeDB) MarkFailure(publicKey []byte) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	record, ok := db.records[key(publicKey)]
+	if !ok {
+		return
+	}
+	record.LastFail = time.Now()
+	record.Fails++
+	_ = db.flush()
+}
+
+/*
+FindFails returns how many consecutive failed probes we've recorded for the
+node identified by publicKey.
+*/
+func (db *nodeDB) FindFails(publicKey []byte) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	record, ok := db.records[key(publicKey)]
+	if !ok {
+		return 0
+	}
+	return record.Fails
+}
+
+/*
+QuerySeeds returns up to n cached nodes that were last confirmed alive within
+maxAge, most-recently-successful first.
+*/
+func (db *nodeDB) QuerySeeds(n int, maxAge time.Duration) []ToxNode {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	candidates := make([]*nodeRecord, 0, len(db.records))
+	cutoff := time.Now().Add(-maxAge)
+	for _, record := range db.records {
+		if record.LastSuccess.After(cutoff) {
+			candidates = append(candidates, record)
+		}
+	}
+	sortRecordsBySuccess(candidates)
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	nodes := make([]ToxNode, len(candidates))
+	for i, record := range candidates {
+		nodes[i] = record.Node
+	}
+	return nodes
+}
+
+/*
+sortRecordsBySuccess orders records by LastSuccess, most recent first. Kept as
+a tiny insertion sort since QuerySeeds only ever runs over a modest cache.
+*/
+func sortRecordsBySuccess(records []*nodeRecord) {
+	for i := 1; i < len(records); i++ {
+		for j := i; j > 0 && records[j].LastSuccess.After(records[j-1].LastSuccess); j-- {
+			records[j], records[j-1] = records[j-1], records[j]
+		}
+	}
+}
+
+/*
+expirer periodically drops records nobody has seen or successfully probed for
+nodeDBExpiration, until Close is called.
+*/
+func (db *nodeDB) expirer() {
+	ticker := time.NewTicker(nodeDBExpireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			db.expireNodes()
+		case <-db.closeCh:
+			return
+		}
+	}
+}
+
+/*
+expireNodes performs a single expiration sweep.
+*/
+func (db *nodeDB) expireNodes() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	cutoff := time.Now().Add(-nodeDBExpiration)
+	for k, record := range db.records {
+		lastActivity := record.LastSeen
+		if record.LastSuccess.After(lastActivity) {
+			lastActivity = record.LastSuccess
+		}
+		if lastActivity.Before(cutoff) {
+			delete(db.records, k)
+		}
+	}
+	_ = db.flush()
+}
+
+/*
+Close stops the background expirer. It is safe to call more than once.
+*/
+func (db *nodeDB) Close() {
+	db.closeOnce.Do(func() {
+		close(db.closeCh)
+	})
+}
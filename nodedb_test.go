@@ -0,0 +1,73 @@
+package toxdynboot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_NodeDB_UpdateAndQuerySeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxdynboot-nodedb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/nodes.json"
+
+	db, err := openNodeDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	node := ToxNode{IPv4: "1.2.3.4", Port: 33445, PublicKey: []byte("0123456789012345678901234567890X")}
+	db.UpdateNode(node, "test")
+	if len(db.QuerySeeds(10, time.Hour)) != 0 {
+		t.Error("expected no seeds before any successful probe")
+	}
+
+	db.LastPongReceived(node.PublicKey, 5*time.Millisecond)
+	seeds := db.QuerySeeds(10, time.Hour)
+	if len(seeds) != 1 || seeds[0].IPv4 != node.IPv4 {
+		t.Errorf("expected the probed node back as a seed, got %v", seeds)
+	}
+	if fails := db.FindFails(node.PublicKey); fails != 0 {
+		t.Errorf("expected 0 fails after a success, got %d", fails)
+	}
+
+	db.MarkFailure(node.PublicKey)
+	if fails := db.FindFails(node.PublicKey); fails != 1 {
+		t.Errorf("expected 1 fail after MarkFailure, got %d", fails)
+	}
+
+	// re-opening should load the persisted records back
+	reopened, err := openNodeDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if len(reopened.QuerySeeds(10, time.Hour)) != 1 {
+		t.Error("expected the cache to survive a reopen")
+	}
+}
+
+func Test_NodeDB_VersionMismatchWipesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "toxdynboot-nodedb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/nodes.json"
+	if err := ioutil.WriteFile(path, []byte(`{"Version":999,"Records":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := openNodeDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if len(db.records) != 0 {
+		t.Error("expected a version mismatch to start from an empty cache")
+	}
+}
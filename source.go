@@ -0,0 +1,226 @@
+package toxdynboot
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+/*
+Source is anything that can produce a list of candidate ToxNodes: a wiki
+scrape, a JSON endpoint, a local file, an intranet mirror, ...
+*/
+type Source interface {
+	Fetch(ctx context.Context) ([]ToxNode, error)
+}
+
+/*
+WikiSource fetches and parses the raw markup of the Tox wiki node list, as
+toxdynboot has always done.
+*/
+type WikiSource struct{}
+
+/*
+Fetch implements Source.
+*/
+func (WikiSource) Fetch(ctx context.Context) ([]ToxNode, error) {
+	return parseNodes(ctx)
+}
+
+/*
+toxJSONNodesURL is the machine-readable counterpart to toxWikiNodesURL,
+maintained by the same community. Its table format is far less likely to
+change shape than the wiki's, so it makes a good second, independent source.
+*/
+const toxJSONNodesURL = "https://nodes.tox.chat/json"
+
+/*
+JSONSource fetches the node list from toxJSONNodesURL.
+*/
+type JSONSource struct{}
+
+/*
+jsonResponse mirrors the document served at toxJSONNodesURL.
+*/
+type jsonResponse struct {
+	Nodes []jsonNode `json:"nodes"`
+}
+
+/*
+jsonNode mirrors a single entry of jsonResponse.
+*/
+type jsonNode struct {
+	IPv4       string `json:"ipv4"`
+	IPv6       string `json:"ipv6"`
+	Port       uint16 `json:"port"`
+	PublicKey  string `json:"public_key"`
+	Maintainer string `json:"maintainer"`
+	Location   string `json:"location"`
+	StatusUDP  bool   `json:"status_udp"`
+	StatusTCP  bool   `json:"status_tcp"`
+	LastPing   int64  `json:"last_ping"`
+	TCPPorts   []int  `json:"tcp_ports"`
+}
+
+/*
+Fetch implements Source.
+*/
+func (JSONSource) Fetch(ctx context.Context) ([]ToxNode, error) {
+	request, err := http.NewRequest(http.MethodGet, toxJSONNodesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var parsed jsonResponse
+	if err := json.Unmarshal(contents, &parsed); err != nil {
+		return nil, err
+	}
+	nodes := make([]ToxNode, 0, len(parsed.Nodes))
+	for _, candidate := range parsed.Nodes {
+		// a node we can't address or verify is useless to us
+		if candidate.PublicKey == "" || (candidate.IPv4 == "" && candidate.IPv6 == "") {
+			continue
+		}
+		publicKey, err := hex.DecodeString(candidate.PublicKey)
+		if err != nil {
+			continue
+		}
+		tcpPorts := make([]uint16, 0, len(candidate.TCPPorts))
+		for _, port := range candidate.TCPPorts {
+			tcpPorts = append(tcpPorts, uint16(port))
+		}
+		nodes = append(nodes, ToxNode{
+			IPv4:       candidate.IPv4,
+			IPv6:       candidate.IPv6,
+			Port:       candidate.Port,
+			PublicKey:  publicKey,
+			Maintainer: candidate.Maintainer,
+			Location:   candidate.Location,
+			TCPPorts:   tcpPorts,
+		})
+	}
+	return nodes, nil
+}
+
+/*
+Registry queries a fixed set of Sources and merges their results into a single
+deduplicated node list via FetchAllFrom. It backs the default, package-level
+FetchAll/FetchAny* functions.
+*/
+type Registry struct {
+	sources []Source
+}
+
+/*
+NewRegistry builds a Registry over the given sources.
+*/
+func NewRegistry(sources ...Source) *Registry {
+	return &Registry{sources: sources}
+}
+
+/*
+Fetch queries every source concurrently and merges the results, see
+FetchAllFrom.
+*/
+func (r *Registry) Fetch(ctx context.Context) ([]ToxNode, error) {
+	return FetchAllFrom(ctx, r.sources...)
+}
+
+/*
+defaultRegistry backs the package-level FetchAll/FetchAny* functions. It
+combines the wiki and the nodes.tox.chat JSON endpoint, so a change to either
+source's format, or either one going down, doesn't take the whole package with
+it.
+*/
+var defaultRegistry = NewRegistry(WikiSource{}, JSONSource{})
+
+/*
+FetchAllFrom queries every given source concurrently and merges their results
+into a single list, deduplicated by PublicKey. Entries for the same node found
+through multiple sources are merged, filling in any field one source left
+blank with the value from another (e.g. a wiki entry's IPv6 address combined
+with a JSON entry's TCPPorts). An error from one source is only fatal if every
+source fails; otherwise the surviving sources' nodes are returned.
+*/
+func FetchAllFrom(ctx context.Context, sources ...Source) ([]ToxNode, error) {
+	type outcome struct {
+		nodes []ToxNode
+		err   error
+	}
+	results := make(chan outcome, len(sources))
+	for _, source := range sources {
+		go func(source Source) {
+			nodes, err := source.Fetch(ctx)
+			results <- outcome{nodes, err}
+		}(source)
+	}
+	merged := make(map[string]ToxNode)
+	var order []string
+	var lastErr error
+	succeeded := 0
+	for i := 0; i < len(sources); i++ {
+		result := <-results
+		if result.err != nil {
+			lastErr = result.err
+			continue
+		}
+		succeeded++
+		for _, node := range result.nodes {
+			key := hex.EncodeToString(node.PublicKey)
+			if key == "" {
+				continue
+			}
+			if existing, ok := merged[key]; ok {
+				merged[key] = mergeNodes(existing, node)
+			} else {
+				merged[key] = node
+				order = append(order, key)
+			}
+		}
+	}
+	if succeeded == 0 {
+		return nil, lastErr
+	}
+	nodes := make([]ToxNode, 0, len(order))
+	for _, key := range order {
+		nodes = append(nodes, merged[key])
+	}
+	return nodes, nil
+}
+
+/*
+mergeNodes combines two sightings of the same node (matched by PublicKey),
+preferring a's fields and falling back to b's wherever a's are blank.
+*/
+func mergeNodes(a, b ToxNode) ToxNode {
+	if a.IPv4 == "" {
+		a.IPv4 = b.IPv4
+	}
+	if a.IPv6 == "" {
+		a.IPv6 = b.IPv6
+	}
+	if a.Port == 0 {
+		a.Port = b.Port
+	}
+	if a.Maintainer == "" {
+		a.Maintainer = b.Maintainer
+	}
+	if a.Location == "" {
+		a.Location = b.Location
+	}
+	if len(a.TCPPorts) == 0 {
+		a.TCPPorts = b.TCPPorts
+	}
+	return a
+}
@@ -0,0 +1,94 @@
+package toxdynboot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_MergeNodes_FillsBlankFields(t *testing.T) {
+	a := ToxNode{IPv4: "1.2.3.4", PublicKey: []byte("key")}
+	b := ToxNode{IPv6: "::1", Port: 33445, Maintainer: "someone", Location: "DE", TCPPorts: []uint16{443}}
+
+	merged := mergeNodes(a, b)
+	if merged.IPv4 != "1.2.3.4" {
+		t.Errorf("expected a's IPv4 to win, got %q", merged.IPv4)
+	}
+	if merged.IPv6 != "::1" {
+		t.Errorf("expected b's IPv6 to fill the blank, got %q", merged.IPv6)
+	}
+	if merged.Port != 33445 {
+		t.Errorf("expected b's Port to fill the blank, got %d", merged.Port)
+	}
+	if merged.Maintainer != "someone" || merged.Location != "DE" {
+		t.Errorf("expected b's Maintainer/Location to fill the blanks, got %+v", merged)
+	}
+	if len(merged.TCPPorts) != 1 || merged.TCPPorts[0] != 443 {
+		t.Errorf("expected b's TCPPorts to fill the blank, got %v", merged.TCPPorts)
+	}
+}
+
+func Test_MergeNodes_PrefersANonBlankFields(t *testing.T) {
+	a := ToxNode{IPv4: "1.2.3.4", Port: 1, Maintainer: "a-wins"}
+	b := ToxNode{IPv4: "5.6.7.8", Port: 2, Maintainer: "b-loses"}
+
+	merged := mergeNodes(a, b)
+	if merged.IPv4 != "1.2.3.4" || merged.Port != 1 || merged.Maintainer != "a-wins" {
+		t.Errorf("expected a's fields to take precedence, got %+v", merged)
+	}
+}
+
+func Test_FetchAllFrom_DedupsByPublicKey(t *testing.T) {
+	key := []byte{0x01, 0x02, 0x03}
+	first := fakeSource{nodes: []ToxNode{{IPv4: "1.2.3.4", PublicKey: key}}}
+	second := fakeSource{nodes: []ToxNode{{IPv6: "::1", PublicKey: key}}}
+
+	nodes, err := FetchAllFrom(context.Background(), first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("expected the two sightings to merge into one node, got %d", len(nodes))
+	}
+	if nodes[0].IPv4 != "1.2.3.4" || nodes[0].IPv6 != "::1" {
+		t.Errorf("expected the merged node to carry both sources' fields, got %+v", nodes[0])
+	}
+}
+
+/*
+failingSource always returns err, for exercising FetchAllFrom's
+one-source-fails/one-succeeds path.
+*/
+type failingSource struct {
+	err error
+}
+
+func (f failingSource) Fetch(ctx context.Context) ([]ToxNode, error) {
+	return nil, f.err
+}
+
+func Test_FetchAllFrom_SurvivesOneSourceFailing(t *testing.T) {
+	good := fakeSource{nodes: []ToxNode{{IPv4: "1.2.3.4", PublicKey: []byte("ok-key")}}}
+	bad := failingSource{err: errors.New("source unavailable")}
+
+	nodes, err := FetchAllFrom(context.Background(), good, bad)
+	if err != nil {
+		t.Fatalf("expected no error since one source succeeded, got %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].IPv4 != "1.2.3.4" {
+		t.Errorf("expected the surviving source's node back, got %v", nodes)
+	}
+}
+
+func Test_FetchAllFrom_ErrorsOnlyIfEverySourceFails(t *testing.T) {
+	first := failingSource{err: errors.New("first down")}
+	second := failingSource{err: errors.New("second down")}
+
+	nodes, err := FetchAllFrom(context.Background(), first, second)
+	if err == nil {
+		t.Fatal("expected an error when every source fails")
+	}
+	if len(nodes) != 0 {
+		t.Errorf("expected no nodes back, got %v", nodes)
+	}
+}
@@ -1,5 +1,10 @@
 package toxdynboot
 
+import (
+	"encoding/hex"
+	"strconv"
+)
+
 /*
 ToxNode is a single possible node candidate. The struct contains all fields that
 we could parse from the wiki in the correct format to use with the Tox wrapper
@@ -12,6 +17,9 @@ type ToxNode struct {
 	PublicKey  []byte
 	Maintainer string
 	Location   string
+	// TCPPorts lists the ports, if any, on which this node also relays TCP.
+	// Only sources that report this (currently JSONSource) populate it.
+	TCPPorts []uint16
 }
 
 /*
@@ -20,6 +28,6 @@ ToxNode.
 */
 func (t *ToxNode) String() string {
 	return "ToxNode{IPv4:" + t.IPv4 + ",IPv6:" + t.IPv6 + ",Port:" +
-		string(t.Port) + ",PublicKey:" + string(t.PublicKey) + ",Maintainer:" +
-		t.Maintainer + ",Location:" + t.Location + "}"
+		strconv.FormatUint(uint64(t.Port), 10) + ",PublicKey:" + hex.EncodeToString(t.PublicKey) +
+		",Maintainer:" + t.Maintainer + ",Location:" + t.Location + "}"
 }